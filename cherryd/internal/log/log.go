@@ -0,0 +1,16 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service Co., Ltd.,
+ * Kitae Kim <superkkt@sds.co.kr>
+ */
+
+package log
+
+// Logger is the minimal logging contract the internal session package
+// depends on.
+type Logger interface {
+	Debug(msg string)
+	Info(msg string)
+	Err(msg string)
+}
@@ -0,0 +1,109 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service Co., Ltd.,
+ * Kitae Kim <superkkt@sds.co.kr>
+ */
+
+package network
+
+import (
+	"git.sds.co.kr/cherry.git/cherryd/openflow"
+	"sync"
+)
+
+// Device represents a single connected OpenFlow switch, as seen by the
+// session layer.
+type Device struct {
+	id      string
+	factory openflow.Factory
+	writer  interface {
+		Write(openflow.Message) error
+	}
+
+	mutex   sync.RWMutex
+	ports   map[uint32]openflow.Port
+	flowTableID    uint8
+	routingTableID uint8
+	pipeline       Pipeline
+}
+
+// NewDevice creates a Device identified by id that writes OpenFlow messages
+// through writer, built with factory.
+func NewDevice(id string, factory openflow.Factory, writer interface {
+	Write(openflow.Message) error
+}) *Device {
+	return &Device{
+		id:      id,
+		factory: factory,
+		writer:  writer,
+		ports:   make(map[uint32]openflow.Port),
+	}
+}
+
+func (r *Device) ID() string {
+	return r.id
+}
+
+func (r *Device) Factory() openflow.Factory {
+	return r.factory
+}
+
+func (r *Device) SendMessage(msg openflow.Message) error {
+	return r.writer.Write(msg)
+}
+
+func (r *Device) AddPort(num uint32, port openflow.Port) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.ports[num] = port
+}
+
+func (r *Device) UpdatePort(num uint32, port openflow.Port) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.ports[num] = port
+}
+
+// SetFlowTableID records the table where flows emitted toward this device
+// should land, e.g. the terminal table of its table-miss chain.
+func (r *Device) SetFlowTableID(tableID uint8) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.flowTableID = tableID
+}
+
+func (r *Device) FlowTableID() uint8 {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.flowTableID
+}
+
+// SetRoutingTableID records the table that termination-MAC entries (see the
+// mymac app) goto, so it can be queried before installing entries into it.
+func (r *Device) SetRoutingTableID(tableID uint8) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.routingTableID = tableID
+}
+
+func (r *Device) RoutingTableID() uint8 {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.routingTableID
+}
+
+// SetPipeline caches the pipeline discovered from this device's TABLE_FEATURES
+// reply, so northbound apps can query its table capabilities before emitting
+// flows.
+func (r *Device) SetPipeline(p Pipeline) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.pipeline = p
+}
+
+func (r *Device) Pipeline() Pipeline {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.pipeline
+}
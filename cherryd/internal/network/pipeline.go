@@ -0,0 +1,89 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service Co., Ltd.,
+ * Kitae Kim <superkkt@sds.co.kr>
+ */
+
+package network
+
+import "git.sds.co.kr/cherry.git/cherryd/openflow/of13"
+
+// PipelineTable describes one table of a switch's pipeline, as reported by
+// its TABLE_FEATURES reply.
+type PipelineTable struct {
+	ID       uint8
+	Hardware bool // false for software-emulated tables (e.g. an overflow table)
+
+	Matches      []uint32
+	NextTables   []uint8
+	Instructions []uint8
+	ApplyActions []uint8
+	WriteActions []uint8
+}
+
+// SupportsAction reports whether action is available to this table through
+// either its apply-actions or write-actions action set.
+func (t PipelineTable) SupportsAction(action uint8) bool {
+	for _, a := range t.ApplyActions {
+		if a == action {
+			return true
+		}
+	}
+	for _, a := range t.WriteActions {
+		if a == action {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NextTable returns the first table this table can GOTO, per its next-tables
+// property.
+func (t PipelineTable) NextTable() (uint8, bool) {
+	if len(t.NextTables) == 0 {
+		return 0, false
+	}
+
+	return t.NextTables[0], true
+}
+
+// CanOutputToController reports whether this table is a terminal table that
+// can punt a packet to the controller.
+func (t PipelineTable) CanOutputToController() bool {
+	return t.SupportsAction(of13.OFPAT_OUTPUT)
+}
+
+// Pipeline is a switch's full table topology, discovered from its
+// TABLE_FEATURES reply.
+type Pipeline struct {
+	Tables []PipelineTable
+}
+
+// Table looks up a table by ID.
+func (p Pipeline) Table(id uint8) (PipelineTable, bool) {
+	for _, t := range p.Tables {
+		if t.ID == id {
+			return t, true
+		}
+	}
+
+	return PipelineTable{}, false
+}
+
+// FirstHardwareTable returns the lowest-ID table flagged as hardware-capable,
+// which is where a table-miss chain should start: hopping a flow into a
+// software-emulated table first (e.g. the HP2920's low-performance Table-200)
+// would needlessly push ordinary traffic through it.
+func (p Pipeline) FirstHardwareTable() (PipelineTable, bool) {
+	var first PipelineTable
+	found := false
+	for _, t := range p.Tables {
+		if t.Hardware && (!found || t.ID < first.ID) {
+			first, found = t, true
+		}
+	}
+
+	return first, found
+}
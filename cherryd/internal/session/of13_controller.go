@@ -8,13 +8,13 @@
 package session
 
 import (
+	"errors"
 	"fmt"
 	"git.sds.co.kr/cherry.git/cherryd/internal/log"
 	"git.sds.co.kr/cherry.git/cherryd/internal/network"
 	"git.sds.co.kr/cherry.git/cherryd/openflow"
 	"git.sds.co.kr/cherry.git/cherryd/openflow/of13"
 	"git.sds.co.kr/cherry.git/cherryd/openflow/trans"
-	"strings"
 )
 
 type OF13Controller struct {
@@ -78,14 +78,6 @@ func (r *OF13Controller) OnGetConfigReply(f openflow.Factory, w trans.Writer, v
 	return nil
 }
 
-func isHP2920_24G(msg openflow.DescReply) bool {
-	return strings.HasPrefix(msg.Manufacturer(), "HP") && strings.HasPrefix(msg.Hardware(), "2920-24G")
-}
-
-func isAS460054_T(msg openflow.DescReply) bool {
-	return strings.Contains(msg.Hardware(), "AS4600-54T")
-}
-
 func (r *OF13Controller) setTableMiss(f openflow.Factory, w trans.Writer, tableID uint8, inst openflow.Instruction) error {
 	match, err := f.NewMatch() // Wildcard
 	if err != nil {
@@ -110,26 +102,49 @@ func (r *OF13Controller) setTableMiss(f openflow.Factory, w trans.Writer, tableI
 	return w.Write(msg)
 }
 
-func (r *OF13Controller) setHP2920TableMiss(f openflow.Factory, w trans.Writer) error {
-	// Table-100 is a hardware table, and Table-200 is a software table
-	// that has very low performance.
+// routingTableID is the table that termination-MAC entries (see the mymac
+// app) goto once a packet's destination MAC is recognized as a router MAC.
+// It must exist on the switch before any such entries are installed.
+const routingTableID = 1
+
+// setDefaultTableMiss installs a single-table 0 -> Controller miss entry. It
+// is kept as a fallback for switches whose TABLE_FEATURES reply can't be
+// turned into a miss chain (e.g. no terminal table reaches the controller).
+func (r *OF13Controller) setDefaultTableMiss(f openflow.Factory, w trans.Writer) error {
 	inst, err := f.NewInstruction()
 	if err != nil {
 		return err
 	}
 
-	// 0 -> 100
-	inst.GotoTable(100)
+	// 0 -> Controller
+	outPort := openflow.NewOutPort()
+	outPort.SetController()
+	action, err := f.NewAction()
+	if err != nil {
+		return err
+	}
+	action.SetOutPort(outPort)
+
+	inst.ApplyAction(action)
 	if err := r.setTableMiss(f, w, 0, inst); err != nil {
 		return fmt.Errorf("failed to set table_miss flow entry: %v", err)
 	}
-	// 100 -> 200
-	inst.GotoTable(200)
-	if err := r.setTableMiss(f, w, 100, inst); err != nil {
-		return fmt.Errorf("failed to set table_miss flow entry: %v", err)
+	r.device.SetFlowTableID(0)
+
+	return r.reserveRoutingTable(f, w)
+}
+
+// reserveRoutingTable installs a Controller miss entry on the routing table
+// and records its ID on the device, so it exists before the mymac app starts
+// installing termination-MAC entries that goto it. It must run after every
+// table-miss path, not just setDefaultTableMiss, since any switch's routing
+// table needs to be ready regardless of how its main miss chain was derived.
+func (r *OF13Controller) reserveRoutingTable(f openflow.Factory, w trans.Writer) error {
+	inst, err := f.NewInstruction()
+	if err != nil {
+		return err
 	}
 
-	// 200 -> Controller
 	outPort := openflow.NewOutPort()
 	outPort.SetController()
 	action, err := f.NewAction()
@@ -139,63 +154,142 @@ func (r *OF13Controller) setHP2920TableMiss(f openflow.Factory, w trans.Writer)
 	action.SetOutPort(outPort)
 
 	inst.ApplyAction(action)
-	if err := r.setTableMiss(f, w, 200, inst); err != nil {
-		return fmt.Errorf("failed to set table_miss flow entry: %v", err)
+	if err := r.setTableMiss(f, w, routingTableID, inst); err != nil {
+		return fmt.Errorf("failed to set table_miss flow entry on the routing table: %v", err)
 	}
-	r.device.SetFlowTableID(200)
+	r.device.SetRoutingTableID(routingTableID)
 
 	return nil
 }
 
-func (r *OF13Controller) setAS4600TableMiss(f openflow.Factory, w trans.Writer) error {
-	// FIXME:
-	// AS460054-T gives an error (type=5, code=1) that means TABLE_FULL
-	// when we install a table-miss flow on Table-0 after we delete all
-	// flows already installed from the switch. Is this a bug of this switch??
+func (r *OF13Controller) OnDescReply(f openflow.Factory, w trans.Writer, v openflow.DescReply) error {
+	// The table-miss chain is derived from the switch's own TABLE_FEATURES
+	// reply instead of hardcoded per-manufacturer/hardware special cases.
+	if err := sendTableFeaturesRequest(f, w); err != nil {
+		return fmt.Errorf("failed to send TABLE_FEATURES_REQUEST: %v", err)
+	}
 
 	return nil
 }
 
-func (r *OF13Controller) setDefaultTableMiss(f openflow.Factory, w trans.Writer) error {
-	inst, err := f.NewInstruction()
+func sendTableFeaturesRequest(f openflow.Factory, w trans.Writer) error {
+	msg, err := f.NewTableFeaturesRequest()
 	if err != nil {
 		return err
 	}
 
-	// 0 -> Controller
-	outPort := openflow.NewOutPort()
-	outPort.SetController()
-	action, err := f.NewAction()
+	return w.Write(msg)
+}
+
+func (r *OF13Controller) OnTableFeaturesReply(f openflow.Factory, w trans.Writer, v openflow.TableFeaturesReply) error {
+	pipeline := newPipeline(v)
+	// Cache the discovered pipeline so northbound apps can query a switch's
+	// table capabilities before emitting flows.
+	r.device.SetPipeline(pipeline)
+
+	chain, err := missChain(pipeline)
 	if err != nil {
+		r.log.Err(fmt.Sprintf("failed to derive a table-miss chain from table features, falling back to the default: %v", err))
+		return r.setDefaultTableMiss(f, w)
+	}
+
+	if err := r.installMissChain(f, w, chain); err != nil {
 		return err
 	}
-	action.SetOutPort(outPort)
+	r.device.SetFlowTableID(chain[len(chain)-1])
 
-	inst.ApplyAction(action)
-	if err := r.setTableMiss(f, w, 0, inst); err != nil {
-		return fmt.Errorf("failed to set table_miss flow entry: %v", err)
+	// The routing table must be reserved regardless of which table-miss path
+	// ran, since the mymac app gotos it from table 0 on every switch, not
+	// just ones that fell back to setDefaultTableMiss.
+	if err := r.reserveRoutingTable(f, w); err != nil {
+		return err
 	}
-	r.device.SetFlowTableID(0)
 
 	return nil
 }
 
-func (r *OF13Controller) OnDescReply(f openflow.Factory, w trans.Writer, v openflow.DescReply) error {
-	var err error
+// newPipeline converts a TABLE_FEATURES multipart reply into a network.Pipeline
+// describing each table's supported match fields, next-table set, and actions.
+func newPipeline(v openflow.TableFeaturesReply) network.Pipeline {
+	tables := make([]network.PipelineTable, 0)
+	for _, t := range v.Tables() {
+		tables = append(tables, network.PipelineTable{
+			ID:           t.ID(),
+			Hardware:     t.Hardware(),
+			Matches:      t.Matches(),
+			NextTables:   t.NextTables(),
+			Instructions: t.Instructions(),
+			ApplyActions: t.ApplyActions(),
+			WriteActions: t.WriteActions(),
+		})
+	}
+
+	return network.Pipeline{Tables: tables}
+}
 
-	// FIXME:
-	// Implement general routines for various table structures of OF1.3 switches
-	// based on table features reply
-	switch {
-	case isHP2920_24G(v):
-		err = r.setHP2920TableMiss(f, w)
-	case isAS460054_T(v):
-		err = r.setAS4600TableMiss(f, w)
-	default:
-		err = r.setDefaultTableMiss(f, w)
+// missChain walks a discovered pipeline from its first hardware-capable table
+// until it reaches a terminal table that supports OUTPUT=CONTROLLER, and
+// returns the ordered table IDs a table-miss goto-chain should hop through.
+// Starting from a software-emulated table (e.g. the HP2920's low-performance
+// Table-200) would push every packet through it even on a table-miss.
+func missChain(p network.Pipeline) ([]uint8, error) {
+	if len(p.Tables) == 0 {
+		return nil, errors.New("table features reply contains no tables")
 	}
 
-	return err
+	cur, ok := p.FirstHardwareTable()
+	if !ok {
+		return nil, errors.New("table features reply has no hardware-capable table")
+	}
+	chain := []uint8{cur.ID}
+	visited := map[uint8]bool{cur.ID: true}
+
+	for !cur.SupportsAction(of13.OFPAT_OUTPUT) {
+		next, ok := cur.NextTable()
+		if !ok || visited[next] {
+			return nil, fmt.Errorf("no terminal table reachable from table %v that supports OUTPUT=CONTROLLER", chain[0])
+		}
+		t, ok := p.Table(next)
+		if !ok {
+			return nil, fmt.Errorf("table %v referenced by table %v is missing from the table features reply", next, cur.ID)
+		}
+		chain = append(chain, next)
+		visited[next] = true
+		cur = t
+	}
+
+	return chain, nil
+}
+
+// installMissChain installs a table-miss flow entry on each table in chain,
+// hopping to the next table via GOTO_TABLE until the terminal table, which
+// outputs to the controller instead.
+func (r *OF13Controller) installMissChain(f openflow.Factory, w trans.Writer, chain []uint8) error {
+	for i, tableID := range chain {
+		inst, err := f.NewInstruction()
+		if err != nil {
+			return err
+		}
+
+		if i < len(chain)-1 {
+			inst.GotoTable(chain[i+1])
+		} else {
+			outPort := openflow.NewOutPort()
+			outPort.SetController()
+			action, err := f.NewAction()
+			if err != nil {
+				return err
+			}
+			action.SetOutPort(outPort)
+			inst.ApplyAction(action)
+		}
+
+		if err := r.setTableMiss(f, w, tableID, inst); err != nil {
+			return fmt.Errorf("failed to set table_miss flow entry on table %v: %v", tableID, err)
+		}
+	}
+
+	return nil
 }
 
 func (r *OF13Controller) OnPortDescReply(f openflow.Factory, w trans.Writer, v openflow.PortDescReply) error {
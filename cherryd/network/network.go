@@ -0,0 +1,344 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+// Package network models the switches and ports the northbound apps operate
+// on, on top of the OpenFlow messages defined in the openflow package.
+package network
+
+import (
+	"fmt"
+	"github.com/superkkt/cherry/cherryd/openflow"
+	"net"
+	"sync"
+	"time"
+)
+
+// Finder locates the devices and ports that make up the network, as known to
+// the controller.
+type Finder interface {
+	Device(id string) (*Device, bool)
+	Devices() []*Device
+}
+
+// Port is a single switch port, as seen by the northbound apps.
+type Port struct {
+	id       string
+	number   uint32
+	device   *Device
+	portDown bool
+	linkDown bool
+}
+
+// NewPort creates the port numbered number on device, identified by id.
+func NewPort(id string, number uint32, device *Device) *Port {
+	return &Port{id: id, number: number, device: device}
+}
+
+func (r *Port) ID() string {
+	return r.id
+}
+
+func (r *Port) Number() uint32 {
+	return r.number
+}
+
+func (r *Port) Device() *Device {
+	return r.device
+}
+
+func (r *Port) IsPortDown() bool {
+	return r.portDown
+}
+
+func (r *Port) IsLinkDown() bool {
+	return r.linkDown
+}
+
+// SetPortDown updates whether this port has been administratively disabled.
+func (r *Port) SetPortDown(down bool) {
+	r.portDown = down
+}
+
+// SetLinkDown updates whether this port's link is down.
+func (r *Port) SetLinkDown(down bool) {
+	r.linkDown = down
+}
+
+// Device is a connected switch, as seen by the northbound apps.
+type Device struct {
+	id      string
+	factory openflow.Factory
+	writer  interface {
+		Write(openflow.Message) error
+	}
+
+	mutex          sync.RWMutex
+	ports          map[uint32]*Port
+	routingTableID uint8
+	localIP        net.IP
+}
+
+// NewDevice creates a Device identified by id that writes OpenFlow messages
+// through writer, built with factory.
+func NewDevice(id string, factory openflow.Factory, writer interface {
+	Write(openflow.Message) error
+}) *Device {
+	return &Device{
+		id:      id,
+		factory: factory,
+		writer:  writer,
+		ports:   make(map[uint32]*Port),
+	}
+}
+
+func (r *Device) ID() string {
+	return r.id
+}
+
+func (r *Device) Factory() openflow.Factory {
+	return r.factory
+}
+
+func (r *Device) SendMessage(msg openflow.Message) error {
+	return r.writer.Write(msg)
+}
+
+func (r *Device) Port(num uint32) (*Port, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	p, ok := r.ports[num]
+	return p, ok
+}
+
+// SetRoutingTableID records the table that MyMAC termination entries (see
+// AddMyMAC) goto, as reserved by the session layer's table-miss setup.
+func (r *Device) SetRoutingTableID(tableID uint8) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.routingTableID = tableID
+}
+
+func (r *Device) RoutingTableID() uint8 {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.routingTableID
+}
+
+// SetLocalIP records the IP address this controller should present as its
+// own on device, e.g. the giaddr a relayed DHCP packet is sent from so the
+// server's reply comes back to the controller instead of nowhere.
+func (r *Device) SetLocalIP(ip net.IP) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.localIP = ip
+}
+
+// LocalIP returns the address previously set by SetLocalIP, or nil if none
+// has been configured for this device.
+func (r *Device) LocalIP() net.IP {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.localIP
+}
+
+// AddMyMAC installs a prioritized FlowMod on the ingress table that matches
+// ETH_DST under macMask (restricted to portID/vlanID when set), instructing
+// the switch to goto the device's routing table. It mirrors SAI's MyMac
+// object: terminating a destination MAC into L3 forwarding instead of
+// switching it.
+func (r *Device) AddMyMAC(priority uint32, portID *uint32, vlanID *uint16, mac, macMask net.HardwareAddr) error {
+	return r.mymacFlowMod(openflow.FlowAdd, priority, portID, vlanID, mac, macMask)
+}
+
+// RemoveMyMAC evicts a previously added MyMAC entry.
+func (r *Device) RemoveMyMAC(priority uint32, portID *uint32, vlanID *uint16, mac, macMask net.HardwareAddr) error {
+	return r.mymacFlowMod(openflow.FlowDelete, priority, portID, vlanID, mac, macMask)
+}
+
+func (r *Device) mymacFlowMod(cmd openflow.FlowModCmd, priority uint32, portID *uint32, vlanID *uint16, mac, macMask net.HardwareAddr) error {
+	match, err := r.factory.NewMatch()
+	if err != nil {
+		return err
+	}
+	if err := match.SetEtherDst(mac, macMask); err != nil {
+		return fmt.Errorf("network: invalid MyMAC destination MAC/mask: %v", err)
+	}
+	if portID != nil {
+		match.SetInPort(*portID)
+	}
+	if vlanID != nil {
+		match.SetVLANID(*vlanID)
+	}
+
+	inst, err := r.factory.NewInstruction()
+	if err != nil {
+		return err
+	}
+	inst.GotoTable(r.RoutingTableID())
+
+	msg, err := r.factory.NewFlowMod(cmd)
+	if err != nil {
+		return err
+	}
+	// Priorities above the uint16 wire range are clamped; callers are
+	// expected to stay within OFP_DEFAULT_PRIORITY's range.
+	if priority > 0xFFFF {
+		priority = 0xFFFF
+	}
+	msg.SetPriority(uint16(priority))
+	msg.SetFlowMatch(match)
+	msg.SetFlowInstruction(inst)
+
+	return r.SendMessage(msg)
+}
+
+// denyFlowPriority and quarantineFlowPriority both sit above
+// controllerPuntPriority and any ordinary forwarding entry, since a deny
+// decision must win regardless of what else would otherwise match.
+const (
+	denyFlowPriority       = 0xFFFE
+	quarantineFlowPriority = 0xFFFF
+)
+
+// AddDenyFlow installs a temporary drop FlowMod on the device matching
+// IN_PORT=port and ETH_SRC=mac, e.g. to stop a single spoofing host without
+// affecting the rest of its port.
+func (r *Device) AddDenyFlow(port uint32, mac net.HardwareAddr, timeout time.Duration) error {
+	match, err := r.factory.NewMatch()
+	if err != nil {
+		return err
+	}
+	match.SetInPort(port)
+	if err := match.SetEtherSrc(mac, net.HardwareAddr([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF})); err != nil {
+		return fmt.Errorf("network: invalid deny flow source MAC: %v", err)
+	}
+
+	inst, err := r.factory.NewInstruction() // no actions: drop
+	if err != nil {
+		return err
+	}
+
+	msg, err := r.factory.NewFlowMod(openflow.FlowAdd)
+	if err != nil {
+		return err
+	}
+	seconds := uint16(timeout / time.Second)
+	msg.SetIdleTimeout(seconds)
+	msg.SetHardTimeout(seconds)
+	msg.SetPriority(denyFlowPriority)
+	msg.SetFlowMatch(match)
+	msg.SetFlowInstruction(inst)
+
+	return r.SendMessage(msg)
+}
+
+// AddPortQuarantine installs a permanent drop FlowMod matching every packet
+// entering on port, quarantining the whole port until RemovePortQuarantine
+// clears it. Unlike AddDenyFlow, it isn't scoped to a single source MAC,
+// since a host that's been caught spoofing repeatedly can't be trusted to
+// keep using the same one.
+func (r *Device) AddPortQuarantine(port uint32) error {
+	match, err := r.factory.NewMatch()
+	if err != nil {
+		return err
+	}
+	match.SetInPort(port)
+
+	inst, err := r.factory.NewInstruction() // no actions: drop
+	if err != nil {
+		return err
+	}
+
+	msg, err := r.factory.NewFlowMod(openflow.FlowAdd)
+	if err != nil {
+		return err
+	}
+	msg.SetIdleTimeout(0)
+	msg.SetHardTimeout(0)
+	msg.SetPriority(quarantineFlowPriority)
+	msg.SetFlowMatch(match)
+	msg.SetFlowInstruction(inst)
+
+	return r.SendMessage(msg)
+}
+
+// RemovePortQuarantine evicts the drop flow AddPortQuarantine installed on
+// port, e.g. once an operator clears the quarantine.
+func (r *Device) RemovePortQuarantine(port uint32) error {
+	match, err := r.factory.NewMatch()
+	if err != nil {
+		return err
+	}
+	match.SetInPort(port)
+
+	msg, err := r.factory.NewFlowMod(openflow.FlowDelete)
+	if err != nil {
+		return err
+	}
+	msg.SetPriority(quarantineFlowPriority)
+	msg.SetFlowMatch(match)
+
+	return r.SendMessage(msg)
+}
+
+// controllerPuntPriority is used for the punt flows AddControllerPuntFlow
+// installs. It sits above the table-miss priority (0) but below any
+// termination-MAC or forwarding entry, since punting DHCP traffic should
+// only kick in when nothing more specific already matched.
+const controllerPuntPriority = 1
+
+// AddControllerPuntFlow installs a permanent FlowMod on the device's ingress
+// table that sends every UDP packet destined to port to the controller,
+// e.g. so dhcpsnoop can observe DHCP server/client traffic.
+func (r *Device) AddControllerPuntFlow(protocol uint8, port uint16) error {
+	match, err := r.factory.NewMatch()
+	if err != nil {
+		return err
+	}
+	match.SetIPProtocol(protocol)
+	match.SetUDPDstPort(port)
+
+	outPort := openflow.NewOutPort()
+	outPort.SetController()
+	action, err := r.factory.NewAction()
+	if err != nil {
+		return err
+	}
+	action.SetOutPort(outPort)
+
+	inst, err := r.factory.NewInstruction()
+	if err != nil {
+		return err
+	}
+	inst.ApplyAction(action)
+
+	msg, err := r.factory.NewFlowMod(openflow.FlowAdd)
+	if err != nil {
+		return err
+	}
+	msg.SetIdleTimeout(0)
+	msg.SetHardTimeout(0)
+	msg.SetPriority(controllerPuntPriority)
+	msg.SetFlowMatch(match)
+	msg.SetFlowInstruction(inst)
+
+	return r.SendMessage(msg)
+}
@@ -0,0 +1,85 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+// Package app defines the northbound Processor chain that the controller
+// feeds device and packet events through.
+package app
+
+import (
+	"github.com/superkkt/cherry/cherryd/network"
+	"github.com/superkkt/cherry/cherryd/protocol"
+)
+
+// Processor handles a single northbound event, deciding whether to pass it
+// on to the next app in the chain.
+type Processor interface {
+	Init() error
+	Name() string
+	String() string
+
+	OnDeviceUp(finder network.Finder, device *network.Device) error
+	OnDeviceDown(finder network.Finder, device *network.Device) error
+	OnPortUp(finder network.Finder, port *network.Port) error
+	OnPortDown(finder network.Finder, port *network.Port) error
+	OnPacketIn(finder network.Finder, ingress *network.Port, eth *protocol.Ethernet) error
+}
+
+// BaseProcessor implements Processor with a default pass-through: every
+// event is forwarded to the next app via Next. Apps embed BaseProcessor and
+// only override the events they actually care about.
+type BaseProcessor struct {
+	Next Processor
+}
+
+func (r *BaseProcessor) OnDeviceUp(finder network.Finder, device *network.Device) error {
+	if r.Next == nil {
+		return nil
+	}
+	return r.Next.OnDeviceUp(finder, device)
+}
+
+func (r *BaseProcessor) OnDeviceDown(finder network.Finder, device *network.Device) error {
+	if r.Next == nil {
+		return nil
+	}
+	return r.Next.OnDeviceDown(finder, device)
+}
+
+func (r *BaseProcessor) OnPortUp(finder network.Finder, port *network.Port) error {
+	if r.Next == nil {
+		return nil
+	}
+	return r.Next.OnPortUp(finder, port)
+}
+
+func (r *BaseProcessor) OnPortDown(finder network.Finder, port *network.Port) error {
+	if r.Next == nil {
+		return nil
+	}
+	return r.Next.OnPortDown(finder, port)
+}
+
+func (r *BaseProcessor) OnPacketIn(finder network.Finder, ingress *network.Port, eth *protocol.Ethernet) error {
+	if r.Next == nil {
+		return nil
+	}
+	return r.Next.OnPacketIn(finder, ingress, eth)
+}
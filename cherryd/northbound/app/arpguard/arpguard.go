@@ -0,0 +1,289 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+// Package arpguard inspects in-flight ARP replies and unicast requests
+// exchanged directly between hosts (the traffic ProxyARP's announcement
+// check doesn't cover), and quarantines ports whose hosts repeatedly claim
+// an IP/MAC pair that contradicts the known-good binding database.
+package arpguard
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/dlintw/goconf"
+	"github.com/superkkt/cherry/cherryd/log"
+	"github.com/superkkt/cherry/cherryd/network"
+	"github.com/superkkt/cherry/cherryd/northbound/app"
+	"github.com/superkkt/cherry/cherryd/protocol"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMismatchThreshold = 3
+	defaultWindow            = 10 * time.Second
+	denyFlowTimeout          = 30 * time.Second
+)
+
+type database interface {
+	FindMAC(ip net.IP, vlan uint16) (mac net.HardwareAddr, ok bool, err error)
+}
+
+// SpoofEvent records a single observed SPA/SHA mismatch.
+type SpoofEvent struct {
+	Time        time.Time
+	DeviceID    string
+	SrcPort     uint32
+	ClaimedIP   net.IP
+	ClaimedMAC  net.HardwareAddr
+	ExpectedMAC net.HardwareAddr
+}
+
+// Quarantine identifies a port that has been deny-flowed after repeated ARP
+// mismatches.
+type Quarantine struct {
+	DeviceID string
+	Port     uint32
+}
+
+type quarantineKey struct {
+	deviceID string
+	port     uint32
+}
+
+type ARPGuard struct {
+	app.BaseProcessor
+	conf *goconf.ConfigFile
+	log  log.Logger
+	db   database
+
+	threshold int
+	window    time.Duration
+
+	finderMutex sync.Mutex
+	finder      network.Finder
+
+	mutex       sync.Mutex
+	mismatches  map[quarantineKey][]time.Time
+	quarantined map[quarantineKey]bool
+	events      []SpoofEvent
+}
+
+func New(conf *goconf.ConfigFile, log log.Logger, db database) *ARPGuard {
+	threshold, err := conf.GetInt("arpguard", "mismatch_threshold")
+	if err != nil || threshold <= 0 {
+		threshold = defaultMismatchThreshold
+	}
+	window := defaultWindow
+	if sec, err := conf.GetInt("arpguard", "window_seconds"); err == nil && sec > 0 {
+		window = time.Duration(sec) * time.Second
+	}
+
+	return &ARPGuard{
+		conf:        conf,
+		log:         log,
+		db:          db,
+		threshold:   threshold,
+		window:      window,
+		mismatches:  make(map[quarantineKey][]time.Time),
+		quarantined: make(map[quarantineKey]bool),
+	}
+}
+
+func (r *ARPGuard) Init() error {
+	return nil
+}
+
+func (r *ARPGuard) Name() string {
+	return "ARPGuard"
+}
+
+func (r *ARPGuard) String() string {
+	return fmt.Sprintf("%v", r.Name())
+}
+
+func (r *ARPGuard) OnPacketIn(finder network.Finder, ingress *network.Port, eth *protocol.Ethernet) error {
+	r.rememberFinder(finder)
+
+	if eth.Type != 0x0806 {
+		return r.BaseProcessor.OnPacketIn(finder, ingress, eth)
+	}
+
+	arp := new(protocol.ARP)
+	if err := arp.UnmarshalBinary(eth.Payload); err != nil {
+		return err
+	}
+	// Gratuitous announcements are ProxyARP's job; we only care about
+	// replies and unicast requests flowing directly between hosts.
+	if isARPAnnouncement(arp) || (arp.Operation != 1 && arp.Operation != 2) {
+		return r.BaseProcessor.OnPacketIn(finder, ingress, eth)
+	}
+	broadcast := bytes.Equal(eth.DstMAC, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF})
+	if arp.Operation == 1 && broadcast {
+		return r.BaseProcessor.OnPacketIn(finder, ingress, eth)
+	}
+
+	key := quarantineKey{deviceID: ingress.Device().ID(), port: ingress.Number()}
+	if r.isQuarantined(key) {
+		r.log.Debug(fmt.Sprintf("arpguard: dropping ARP from quarantined port %v", ingress.ID()))
+		return nil
+	}
+
+	var vlan uint16
+	if eth.VLAN != nil {
+		vlan = *eth.VLAN
+	}
+	expected, ok, err := r.db.FindMAC(arp.SPA, vlan)
+	if err != nil {
+		return err
+	}
+	if !ok || !bytes.Equal(expected, arp.SHA) {
+		return r.handleMismatch(key, ingress, arp, expected)
+	}
+
+	return r.BaseProcessor.OnPacketIn(finder, ingress, eth)
+}
+
+func (r *ARPGuard) rememberFinder(finder network.Finder) {
+	r.finderMutex.Lock()
+	defer r.finderMutex.Unlock()
+	r.finder = finder
+}
+
+func isARPAnnouncement(request *protocol.ARP) bool {
+	sameAddr := request.SPA.Equal(request.TPA)
+	zeroTarget := bytes.Compare(request.THA, []byte{0, 0, 0, 0, 0, 0}) == 0
+
+	return sameAddr && zeroTarget
+}
+
+func (r *ARPGuard) handleMismatch(key quarantineKey, ingress *network.Port, arp *protocol.ARP, expected net.HardwareAddr) error {
+	event := SpoofEvent{
+		Time:        time.Now(),
+		DeviceID:    key.deviceID,
+		SrcPort:     key.port,
+		ClaimedIP:   arp.SPA,
+		ClaimedMAC:  arp.SHA,
+		ExpectedMAC: expected,
+	}
+	r.log.Info(fmt.Sprintf("arpguard: ARP spoof suspected: src_port=%v claimed_ip=%v claimed_mac=%v expected_mac=%v",
+		ingress.ID(), event.ClaimedIP, event.ClaimedMAC, event.ExpectedMAC))
+
+	if err := ingress.Device().AddDenyFlow(ingress.Number(), arp.SHA, denyFlowTimeout); err != nil {
+		return fmt.Errorf("arpguard: failed to install deny flow: %v", err)
+	}
+
+	return r.recordMismatch(key, ingress.Device(), event)
+}
+
+// recordMismatch appends event to the recent history for key and, once the
+// number of mismatches within the configured window reaches the threshold,
+// quarantines the port via a permanent port-wide deny flow until an
+// operator clears it with ClearQuarantine.
+func (r *ARPGuard) recordMismatch(key quarantineKey, device *network.Device, event SpoofEvent) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.events = append(r.events, event)
+
+	cutoff := event.Time.Add(-r.window)
+	fresh := r.mismatches[key][:0]
+	for _, t := range r.mismatches[key] {
+		if t.After(cutoff) {
+			fresh = append(fresh, t)
+		}
+	}
+	fresh = append(fresh, event.Time)
+	r.mismatches[key] = fresh
+
+	if len(fresh) >= r.threshold && !r.quarantined[key] {
+		if err := device.AddPortQuarantine(key.port); err != nil {
+			return fmt.Errorf("arpguard: failed to quarantine port: %v", err)
+		}
+		r.quarantined[key] = true
+		r.log.Info(fmt.Sprintf("arpguard: quarantining device=%v port=%v after %v ARP mismatches within %v",
+			key.deviceID, key.port, len(fresh), r.window))
+	}
+
+	return nil
+}
+
+func (r *ARPGuard) isQuarantined(key quarantineKey) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.quarantined[key]
+}
+
+// Quarantines returns the ports currently quarantined, so a management tool
+// can enumerate and clear them.
+func (r *ARPGuard) Quarantines() []Quarantine {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	list := make([]Quarantine, 0, len(r.quarantined))
+	for k, quarantined := range r.quarantined {
+		if !quarantined {
+			continue
+		}
+		list = append(list, Quarantine{DeviceID: k.deviceID, Port: k.port})
+	}
+
+	return list
+}
+
+// ClearQuarantine lifts a previously placed quarantine, e.g. after an
+// operator confirms the host has been remediated, removing the port-wide
+// deny flow AddPortQuarantine installed.
+func (r *ARPGuard) ClearQuarantine(deviceID string, port uint32) error {
+	r.finderMutex.Lock()
+	finder := r.finder
+	r.finderMutex.Unlock()
+
+	if finder != nil {
+		if device, ok := finder.Device(deviceID); ok {
+			if err := device.RemovePortQuarantine(port); err != nil {
+				return fmt.Errorf("arpguard: failed to clear port quarantine: %v", err)
+			}
+		}
+	}
+
+	key := quarantineKey{deviceID: deviceID, port: port}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.quarantined, key)
+	delete(r.mismatches, key)
+
+	return nil
+}
+
+// Events returns recently observed spoof events, oldest first.
+func (r *ARPGuard) Events() []SpoofEvent {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	list := make([]SpoofEvent, len(r.events))
+	copy(list, r.events)
+
+	return list
+}
@@ -0,0 +1,344 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+// Package dhcpsnoop traps DHCP traffic and maintains a (VLAN, IP) -> (MAC,
+// ingress port, lease expiry) binding table from what it observes, so other
+// apps (e.g. proxyarp) can answer from live DHCP state instead of static
+// configuration.
+package dhcpsnoop
+
+import (
+	"fmt"
+	"github.com/dlintw/goconf"
+	"github.com/superkkt/cherry/cherryd/log"
+	"github.com/superkkt/cherry/cherryd/network"
+	"github.com/superkkt/cherry/cherryd/northbound/app"
+	"github.com/superkkt/cherry/cherryd/openflow"
+	"github.com/superkkt/cherry/cherryd/protocol"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	dhcpServerPort   = 67
+	dhcpClientPort   = 68
+	defaultLeaseTime = 24 * time.Hour
+)
+
+// Binding is a single DHCP-snooped lease.
+type Binding struct {
+	VLAN        uint16
+	IP          net.IP
+	MAC         net.HardwareAddr
+	IngressPort uint32
+	LeaseExpiry time.Time
+}
+
+type bindingKey struct {
+	vlan uint16
+	ip   string
+}
+
+type DHCPSnoop struct {
+	app.BaseProcessor
+	conf *goconf.ConfigFile
+	log  log.Logger
+
+	mutex    sync.Mutex
+	bindings map[bindingKey]Binding
+	// trusted marks, per device ID, the ingress ports where DHCP server
+	// traffic (OFFER/ACK/NAK) is expected, e.g. uplinks toward the DHCP
+	// server. Server traffic seen elsewhere is dropped as spoofed.
+	trusted map[string]map[uint32]bool
+
+	quit chan struct{}
+}
+
+func New(conf *goconf.ConfigFile, log log.Logger) *DHCPSnoop {
+	return &DHCPSnoop{
+		conf:     conf,
+		log:      log,
+		bindings: make(map[bindingKey]Binding),
+		trusted:  make(map[string]map[uint32]bool),
+		quit:     make(chan struct{}),
+	}
+}
+
+func (r *DHCPSnoop) Init() error {
+	go r.expireLoop()
+	return nil
+}
+
+func (r *DHCPSnoop) Name() string {
+	return "DHCPSnoop"
+}
+
+func (r *DHCPSnoop) String() string {
+	return fmt.Sprintf("%v", r.Name())
+}
+
+// TrustPort designates port on device as an uplink toward a DHCP server, so
+// server-to-client messages (OFFER/ACK/NAK) arriving there are accepted.
+func (r *DHCPSnoop) TrustPort(device *network.Device, port uint32) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	id := device.ID()
+	if r.trusted[id] == nil {
+		r.trusted[id] = make(map[uint32]bool)
+	}
+	r.trusted[id][port] = true
+}
+
+func (r *DHCPSnoop) isTrusted(device *network.Device, port uint32) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.trusted[device.ID()][port]
+}
+
+func (r *DHCPSnoop) OnDeviceUp(finder network.Finder, device *network.Device) error {
+	if err := device.AddControllerPuntFlow(protocol.IPProtocolUDP, dhcpServerPort); err != nil {
+		return fmt.Errorf("dhcpsnoop: failed to install DHCP server-port punt flow: %v", err)
+	}
+	if err := device.AddControllerPuntFlow(protocol.IPProtocolUDP, dhcpClientPort); err != nil {
+		return fmt.Errorf("dhcpsnoop: failed to install DHCP client-port punt flow: %v", err)
+	}
+
+	return r.BaseProcessor.OnDeviceUp(finder, device)
+}
+
+func (r *DHCPSnoop) OnPacketIn(finder network.Finder, ingress *network.Port, eth *protocol.Ethernet) error {
+	if eth.Type != 0x0800 {
+		return r.BaseProcessor.OnPacketIn(finder, ingress, eth)
+	}
+
+	ip := new(protocol.IPv4)
+	if err := ip.UnmarshalBinary(eth.Payload); err != nil {
+		return err
+	}
+	if ip.Protocol != protocol.IPProtocolUDP {
+		return r.BaseProcessor.OnPacketIn(finder, ingress, eth)
+	}
+
+	udp := new(protocol.UDP)
+	if err := udp.UnmarshalBinary(ip.Payload); err != nil {
+		return err
+	}
+	if !isDHCP(udp) {
+		return r.BaseProcessor.OnPacketIn(finder, ingress, eth)
+	}
+
+	dhcp := new(protocol.DHCP)
+	if err := dhcp.UnmarshalBinary(udp.Payload); err != nil {
+		return err
+	}
+
+	var vlan uint16
+	if eth.VLAN != nil {
+		vlan = *eth.VLAN
+	}
+
+	switch dhcp.MessageType() {
+	case protocol.DHCPOffer, protocol.DHCPAck, protocol.DHCPNak:
+		if !r.isTrusted(ingress.Device(), ingress.Number()) {
+			r.log.Info(fmt.Sprintf("dhcpsnoop: dropping DHCP server message from untrusted port %v", ingress.ID()))
+			return nil
+		}
+		if dhcp.MessageType() != protocol.DHCPNak {
+			if err := r.learnBinding(ingress, dhcp, vlan); err != nil {
+				return err
+			}
+		}
+		return r.BaseProcessor.OnPacketIn(finder, ingress, eth)
+	case protocol.DHCPRelease, protocol.DHCPDecline:
+		return r.forgetBinding(dhcp, vlan)
+	case protocol.DHCPDiscover, protocol.DHCPRequest:
+		if relayed, err := r.relayToServer(ingress, dhcp); relayed || err != nil {
+			return err
+		}
+		return r.BaseProcessor.OnPacketIn(finder, ingress, eth)
+	default:
+		return r.BaseProcessor.OnPacketIn(finder, ingress, eth)
+	}
+}
+
+func isDHCP(udp *protocol.UDP) bool {
+	return (udp.SrcPort == dhcpServerPort && udp.DstPort == dhcpClientPort) ||
+		(udp.SrcPort == dhcpClientPort && udp.DstPort == dhcpServerPort)
+}
+
+// learnBinding records the lease handed out in a server OFFER/ACK, rejecting
+// it if it didn't arrive on a trusted uplink port. vlan is the 802.1Q tag the
+// packet itself carried, not anything derived from the DHCP payload: a
+// directly-attached client's own messages never set option 82, so that can't
+// distinguish VLANs.
+func (r *DHCPSnoop) learnBinding(ingress *network.Port, dhcp *protocol.DHCP, vlan uint16) error {
+	if !r.isTrusted(ingress.Device(), ingress.Number()) {
+		r.log.Info(fmt.Sprintf("dhcpsnoop: dropping DHCP server message from untrusted port %v", ingress.ID()))
+		return nil
+	}
+
+	lease := dhcp.LeaseTime()
+	if lease == 0 {
+		lease = defaultLeaseTime
+	}
+
+	key := bindingKey{vlan: vlan, ip: dhcp.YourIP().String()}
+	binding := Binding{
+		VLAN:        vlan,
+		IP:          dhcp.YourIP(),
+		MAC:         dhcp.ClientMAC(),
+		IngressPort: ingress.Number(),
+		LeaseExpiry: time.Now().Add(lease),
+	}
+
+	r.mutex.Lock()
+	r.bindings[key] = binding
+	r.mutex.Unlock()
+
+	r.log.Debug(fmt.Sprintf("dhcpsnoop: bound %v to %v (vlan=%v)", binding.IP, binding.MAC, binding.VLAN))
+
+	return nil
+}
+
+func (r *DHCPSnoop) forgetBinding(dhcp *protocol.DHCP, vlan uint16) error {
+	key := bindingKey{vlan: vlan, ip: dhcp.ClientIP().String()}
+
+	r.mutex.Lock()
+	delete(r.bindings, key)
+	r.mutex.Unlock()
+
+	return nil
+}
+
+// relayToServer forwards a client's DISCOVER/REQUEST to a configured relay
+// target via packet-out, so clients in a VLAN without a local DHCP server
+// can still reach one in another subnet. It returns false if no relay target
+// is configured, so the caller can fall back to ordinary flooding.
+func (r *DHCPSnoop) relayToServer(ingress *network.Port, dhcp *protocol.DHCP) (bool, error) {
+	server, ok := r.relayTarget()
+	if !ok {
+		return false, nil
+	}
+
+	giaddr := ingress.Device().LocalIP()
+	if giaddr == nil {
+		return true, fmt.Errorf("dhcpsnoop: cannot relay DHCP packet: device %v has no local IP configured", ingress.Device().ID())
+	}
+
+	packet, err := dhcp.Relay(server, giaddr)
+	if err != nil {
+		return true, fmt.Errorf("dhcpsnoop: failed to build relayed DHCP packet: %v", err)
+	}
+
+	return true, sendPacketOut(ingress.Device(), packet)
+}
+
+// sendPacketOut floods packet out of device via a PacketOut, the same way
+// proxyarp sends its ARP announcements.
+func sendPacketOut(device *network.Device, packet []byte) error {
+	f := device.Factory()
+
+	inPort := openflow.NewInPort()
+	inPort.SetController()
+
+	action, err := f.NewAction()
+	if err != nil {
+		return err
+	}
+	// Flood toward the relay target: every port of the device, the uplinks
+	// included, gets a copy.
+	action.SetOutPort(openflow.NewOutPort())
+
+	out, err := f.NewPacketOut()
+	if err != nil {
+		return err
+	}
+	out.SetInPort(inPort)
+	out.SetAction(action)
+	out.SetData(packet)
+
+	return device.SendMessage(out)
+}
+
+// relayTarget reads the DHCP relay server address from the [dhcpsnoop]
+// section of the configuration file, if one is configured.
+func (r *DHCPSnoop) relayTarget() (net.IP, bool) {
+	v, err := r.conf.GetString("dhcpsnoop", "relay_server")
+	if err != nil || len(v) == 0 {
+		return nil, false
+	}
+
+	ip := net.ParseIP(v)
+	if ip == nil {
+		r.log.Err(fmt.Sprintf("dhcpsnoop: invalid relay_server address: %v", v))
+		return nil, false
+	}
+
+	return ip, true
+}
+
+func (r *DHCPSnoop) expireLoop() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.expire()
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+func (r *DHCPSnoop) expire() {
+	now := time.Now()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for k, b := range r.bindings {
+		if now.After(b.LeaseExpiry) {
+			delete(r.bindings, k)
+		}
+	}
+}
+
+// FindMAC answers MAC lookups from live DHCP-snooped state rather than static
+// config. It implements the database interface consumed by the arpguard app;
+// the proxyarp app's database interface also requires ListGateways, which
+// DHCPSnoop does not provide. vlan scopes the lookup so two VLANs sharing an IP resolve
+// to their own binding instead of whichever one happens to be stored first.
+func (r *DHCPSnoop) FindMAC(ip net.IP, vlan uint16) (mac net.HardwareAddr, ok bool, err error) {
+	now := time.Now()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, b := range r.bindings {
+		if b.VLAN == vlan && b.IP.Equal(ip) && now.Before(b.LeaseExpiry) {
+			return b.MAC, true, nil
+		}
+	}
+
+	return nil, false, nil
+}
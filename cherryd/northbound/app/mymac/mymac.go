@@ -0,0 +1,163 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+// Package mymac installs termination-MAC ACL entries, modeled on SAI's MyMac
+// object, that redirect router-destined traffic into a switch's L3 routing
+// table instead of letting it fall through to ordinary L2 forwarding.
+package mymac
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/dlintw/goconf"
+	"github.com/superkkt/cherry/cherryd/log"
+	"github.com/superkkt/cherry/cherryd/network"
+	"github.com/superkkt/cherry/cherryd/northbound/app"
+	"net"
+	"sort"
+	"sync"
+)
+
+// Entry is a single termination-MAC rule: a packet whose destination MAC
+// matches MAC (under MACMask), and optionally arrives on PortID and/or
+// carries VLANID, is terminated into the device's routing table instead of
+// being switched. PortID and VLANID are nil when the entry isn't restricted
+// to a particular port or VLAN.
+type Entry struct {
+	Priority uint32
+	PortID   *uint32
+	VLANID   *uint16
+	MAC      net.HardwareAddr
+	MACMask  net.HardwareAddr
+}
+
+func (a Entry) equal(b Entry) bool {
+	if a.Priority != b.Priority {
+		return false
+	}
+	if !bytes.Equal(a.MAC, b.MAC) || !bytes.Equal(a.MACMask, b.MACMask) {
+		return false
+	}
+	if !equalUint32Ptr(a.PortID, b.PortID) {
+		return false
+	}
+	if !equalUint16Ptr(a.VLANID, b.VLANID) {
+		return false
+	}
+
+	return true
+}
+
+func equalUint32Ptr(a, b *uint32) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func equalUint16Ptr(a, b *uint16) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+type MyMAC struct {
+	app.BaseProcessor
+	conf *goconf.ConfigFile
+	log  log.Logger
+
+	mutex   sync.Mutex
+	entries map[string][]Entry // keyed by device ID, priority-ordered (highest first)
+}
+
+func New(conf *goconf.ConfigFile, log log.Logger) *MyMAC {
+	return &MyMAC{
+		conf:    conf,
+		log:     log,
+		entries: make(map[string][]Entry),
+	}
+}
+
+func (r *MyMAC) Init() error {
+	return nil
+}
+
+func (r *MyMAC) Name() string {
+	return "MyMAC"
+}
+
+func (r *MyMAC) String() string {
+	return fmt.Sprintf("%v", r.Name())
+}
+
+// Add registers entry on device: it installs a prioritized FlowMod on the
+// device's ingress table that matches ETH_DST (masked by entry.MACMask), and
+// optionally IN_PORT/VLAN_VID, with an instruction that gotos the device's
+// routing table.
+func (r *MyMAC) Add(device *network.Device, entry Entry) error {
+	if err := device.AddMyMAC(entry.Priority, entry.PortID, entry.VLANID, entry.MAC, entry.MACMask); err != nil {
+		return fmt.Errorf("mymac: failed to add entry: %v", err)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	id := device.ID()
+	r.entries[id] = append(r.entries[id], entry)
+	sort.SliceStable(r.entries[id], func(i, j int) bool {
+		return r.entries[id][i].Priority > r.entries[id][j].Priority
+	})
+
+	return nil
+}
+
+// Remove evicts a previously added entry from device.
+func (r *MyMAC) Remove(device *network.Device, entry Entry) error {
+	if err := device.RemoveMyMAC(entry.Priority, entry.PortID, entry.VLANID, entry.MAC, entry.MACMask); err != nil {
+		return fmt.Errorf("mymac: failed to remove entry: %v", err)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	id := device.ID()
+	for i, e := range r.entries[id] {
+		if e.equal(entry) {
+			r.entries[id] = append(r.entries[id][:i], r.entries[id][i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
+
+// List returns the entries currently registered on device, ordered from
+// highest to lowest priority.
+func (r *MyMAC) List(device *network.Device) []Entry {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	list := make([]Entry, len(r.entries[device.ID()]))
+	copy(list, r.entries[device.ID()])
+
+	return list
+}
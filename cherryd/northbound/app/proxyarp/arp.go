@@ -31,17 +31,39 @@ import (
 	"github.com/superkkt/cherry/cherryd/openflow"
 	"github.com/superkkt/cherry/cherryd/protocol"
 	"net"
+	"sync"
+	"time"
 )
 
+// defaultGatewayAnnounceInterval is used when [proxyarp]/gateway_announce_interval
+// isn't set in the configuration file.
+const defaultGatewayAnnounceInterval = 300 * time.Second
+
 type ProxyARP struct {
 	app.BaseProcessor
 	conf *goconf.ConfigFile
 	log  log.Logger
 	db   database
+
+	once   sync.Once
+	mutex  sync.Mutex
+	finder network.Finder
+}
+
+// Gateway is a per-VLAN virtual router address (e.g. a VRRP virtual MAC or a
+// VLAN's SVI) that ProxyARP announces on device-up, periodically, and on
+// link-up, so newly-joined switches learn it without waiting for a host to
+// ARP for it.
+type Gateway struct {
+	VLAN  uint16
+	IP    net.IP
+	MAC   net.HardwareAddr
+	Ports []uint32 // restricts the announcement to these ports; empty floods
 }
 
 type database interface {
-	FindMAC(ip net.IP) (mac net.HardwareAddr, ok bool, err error)
+	FindMAC(ip net.IP, vlan uint16) (mac net.HardwareAddr, ok bool, err error)
+	ListGateways() ([]Gateway, error)
 }
 
 func New(conf *goconf.ConfigFile, log log.Logger, db database) *ProxyARP {
@@ -72,6 +94,10 @@ func (r *ProxyARP) OnPacketIn(finder network.Finder, ingress *network.Port, eth
 	if err := arp.UnmarshalBinary(eth.Payload); err != nil {
 		return err
 	}
+	var vlan uint16
+	if eth.VLAN != nil {
+		vlan = *eth.VLAN
+	}
 	// ARP request?
 	if arp.Operation != 1 {
 		r.log.Debug(fmt.Sprintf("ProxyARP: drop ARP packet whose type is not a requesat.. ingress=%v, type=%v", ingress.ID(), arp.Operation))
@@ -81,7 +107,7 @@ func (r *ProxyARP) OnPacketIn(finder network.Finder, ingress *network.Port, eth
 	// Pass ARP announcements packets if it has valid source IP & MAC addresses
 	if isARPAnnouncement(arp) {
 		r.log.Debug(fmt.Sprintf("ProxyARP: received ARP announcements.. ingress=%v", ingress.ID()))
-		valid, err := r.isValidARPAnnouncement(arp)
+		valid, err := r.isValidARPAnnouncement(arp, vlan)
 		if err != nil {
 			return err
 		}
@@ -94,7 +120,7 @@ func (r *ProxyARP) OnPacketIn(finder network.Finder, ingress *network.Port, eth
 		// Pass valid ARP announcements to the network
 		return r.BaseProcessor.OnPacketIn(finder, ingress, eth)
 	}
-	mac, ok, err := r.db.FindMAC(arp.TPA)
+	mac, ok, err := r.db.FindMAC(arp.TPA, vlan)
 	if err != nil {
 		return err
 	}
@@ -149,9 +175,9 @@ func isARPAnnouncement(request *protocol.ARP) bool {
 	return true
 }
 
-func (r *ProxyARP) isValidARPAnnouncement(request *protocol.ARP) (bool, error) {
+func (r *ProxyARP) isValidARPAnnouncement(request *protocol.ARP, vlan uint16) (bool, error) {
 	// Trusted MAC address?
-	mac, ok, err := r.db.FindMAC(request.SPA)
+	mac, ok, err := r.db.FindMAC(request.SPA, vlan)
 	if err != nil {
 		return false, err
 	}
@@ -183,33 +209,126 @@ func (r *ProxyARP) String() string {
 	return fmt.Sprintf("%v", r.Name())
 }
 
-func makeARPAnnouncement(ip net.IP, mac net.HardwareAddr) ([]byte, error) {
-	v := protocol.NewARPRequest(mac, ip, ip)
+// makeGatewayAnnouncement builds a gratuitous ARP announcement for gw, tagged
+// with its VLAN when one is set.
+func makeGatewayAnnouncement(gw Gateway) ([]byte, error) {
+	v := protocol.NewARPRequest(gw.MAC, gw.IP, gw.IP)
 	anon, err := v.MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
 	eth := protocol.Ethernet{
-		SrcMAC:  mac,
+		SrcMAC:  gw.MAC,
 		DstMAC:  net.HardwareAddr([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}),
 		Type:    0x0806,
 		Payload: anon,
 	}
+	if gw.VLAN != 0 {
+		eth.VLAN = &gw.VLAN
+	}
 
 	return eth.MarshalBinary()
 }
 
 func (r *ProxyARP) OnDeviceUp(finder network.Finder, device *network.Device) error {
-	// FIXME: Remove this fixed IP and MAC addresses and read them from the database
-	anon, err := makeARPAnnouncement(net.IPv4(223, 130, 122, 1), net.HardwareAddr([]byte{0x00, 0x01, 0xe8, 0x8b, 0x64, 0x42}))
+	r.rememberFinder(finder)
+
+	if err := r.announceGateways(device); err != nil {
+		return fmt.Errorf("announcing gateways: %v", err)
+	}
+	r.once.Do(func() {
+		go r.reannounceGatewaysPeriodically()
+	})
+
+	return r.BaseProcessor.OnDeviceUp(finder, device)
+}
+
+func (r *ProxyARP) OnPortUp(finder network.Finder, port *network.Port) error {
+	r.rememberFinder(finder)
+
+	// Re-announce gateways on link-up so a switch that just joined the
+	// network (or whose link just bounced) learns the gateway MACs without
+	// waiting for a host on it to ARP.
+	if err := r.announceGateways(port.Device()); err != nil {
+		r.log.Err(fmt.Sprintf("ProxyARP: gateway re-announcement on link-up failed: %v", err))
+	}
+
+	return r.BaseProcessor.OnPortUp(finder, port)
+}
+
+func (r *ProxyARP) rememberFinder(finder network.Finder) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.finder = finder
+}
+
+// announceGateways reads the configured gateways from the database and sends
+// one tagged ARP announcement per gateway out of device, restricted to its
+// configured ports or flooded if it has none.
+func (r *ProxyARP) announceGateways(device *network.Device) error {
+	gateways, err := r.db.ListGateways()
 	if err != nil {
-		return fmt.Errorf("making ARP announcement: %v", err)
+		return fmt.Errorf("listing gateways: %v", err)
 	}
-	if err := sendARPAnnouncement(device, anon); err != nil {
-		return fmt.Errorf("sending ARP announcement: %v", err)
+
+	for _, gw := range gateways {
+		if err := r.announceGateway(device, gw); err != nil {
+			return fmt.Errorf("announcing gateway %v (vlan=%v): %v", gw.IP, gw.VLAN, err)
+		}
 	}
 
-	return r.BaseProcessor.OnDeviceUp(finder, device)
+	return nil
+}
+
+func (r *ProxyARP) announceGateway(device *network.Device, gw Gateway) error {
+	anon, err := makeGatewayAnnouncement(gw)
+	if err != nil {
+		return err
+	}
+
+	if len(gw.Ports) == 0 {
+		return sendARPAnnouncement(device, anon)
+	}
+	for _, port := range gw.Ports {
+		if err := sendARPAnnouncementToPort(device, anon, port); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reannounceGatewaysPeriodically re-sends every device's gateway
+// announcements on the interval configured by
+// [proxyarp]/gateway_announce_interval, so their ARP cache entries don't
+// expire even when no host happens to ARP for them.
+func (r *ProxyARP) reannounceGatewaysPeriodically() {
+	ticker := time.NewTicker(r.gatewayAnnounceInterval())
+	defer ticker.Stop()
+
+	for range ticker.C {
+		r.mutex.Lock()
+		finder := r.finder
+		r.mutex.Unlock()
+		if finder == nil {
+			continue
+		}
+
+		for _, device := range finder.Devices() {
+			if err := r.announceGateways(device); err != nil {
+				r.log.Err(fmt.Sprintf("ProxyARP: periodic gateway re-announcement failed: %v", err))
+			}
+		}
+	}
+}
+
+func (r *ProxyARP) gatewayAnnounceInterval() time.Duration {
+	sec, err := r.conf.GetInt("proxyarp", "gateway_announce_interval")
+	if err != nil || sec <= 0 {
+		return defaultGatewayAnnounceInterval
+	}
+
+	return time.Duration(sec) * time.Second
 }
 
 func sendARPAnnouncement(device *network.Device, packet []byte) error {
@@ -235,3 +354,31 @@ func sendARPAnnouncement(device *network.Device, packet []byte) error {
 
 	return device.SendMessage(out)
 }
+
+// sendARPAnnouncementToPort is sendARPAnnouncement restricted to a single
+// egress port, used to confine a gateway's announcement to its configured
+// ports instead of flooding the whole device.
+func sendARPAnnouncementToPort(device *network.Device, packet []byte, port uint32) error {
+	f := device.Factory()
+
+	inPort := openflow.NewInPort()
+	inPort.SetController()
+
+	outPort := openflow.NewOutPort()
+	outPort.SetValue(port)
+	action, err := f.NewAction()
+	if err != nil {
+		return err
+	}
+	action.SetOutPort(outPort)
+
+	out, err := f.NewPacketOut()
+	if err != nil {
+		return err
+	}
+	out.SetInPort(inPort)
+	out.SetAction(action)
+	out.SetData(packet)
+
+	return device.SendMessage(out)
+}
@@ -0,0 +1,19 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service Co., Ltd.,
+ * Kitae Kim <superkkt@sds.co.kr>
+ */
+
+// Package of13 holds OpenFlow 1.3 wire constants.
+package of13
+
+const (
+	// OFPP_MAX is the highest port number that refers to a physical/logical
+	// switch port rather than a reserved port (CONTROLLER, FLOOD, ...).
+	OFPP_MAX uint32 = 0xffffff00
+
+	// OFPAT_OUTPUT is the OFPAT_OUTPUT action type, used to recognize a
+	// table that can punt a packet to the controller.
+	OFPAT_OUTPUT uint8 = 0
+)
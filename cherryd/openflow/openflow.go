@@ -0,0 +1,170 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service Co., Ltd.,
+ * Kitae Kim <superkkt@sds.co.kr>
+ */
+
+// Package openflow defines the OpenFlow message and Factory abstractions
+// shared by the controller's session and northbound layers.
+package openflow
+
+// Message is implemented by every OpenFlow message a Factory can build and a
+// trans.Writer can send.
+type Message interface {
+	Version() uint8
+	Type() uint8
+}
+
+// Factory builds protocol-version-specific OpenFlow messages and building
+// blocks (matches, instructions, actions, ...).
+type Factory interface {
+	NewHello() (Hello, error)
+	NewSetConfig() (Message, error)
+	NewFeaturesRequest() (Message, error)
+	NewBarrierRequest() (Message, error)
+	NewFlowMod(cmd FlowModCmd) (FlowMod, error)
+	NewMatch() (Match, error)
+	NewInstruction() (Instruction, error)
+	NewAction() (Action, error)
+	NewPacketOut() (PacketOut, error)
+	NewDescriptionRequest() (Message, error)
+	NewPortDescriptionRequest() (Message, error)
+	NewTableFeaturesRequest() (Message, error)
+}
+
+// FlowModCmd selects the operation a FlowMod performs.
+type FlowModCmd int
+
+const (
+	FlowAdd FlowModCmd = iota
+	FlowModify
+	FlowDelete
+)
+
+type Hello interface {
+	Message
+}
+
+type Error interface {
+	Message
+}
+
+type FeaturesReply interface {
+	Message
+}
+
+type GetConfigReply interface {
+	Message
+}
+
+// DescReply is an OFPT_MULTIPART_REPLY/OFPMP_DESC reply.
+type DescReply interface {
+	Message
+	Manufacturer() string
+	Hardware() string
+}
+
+// Port is a switch port, as reported by a PortDescReply or a PortStatus.
+type Port interface {
+	Number() uint32
+	IsPortDown() bool
+	IsLinkDown() bool
+}
+
+type PortDescReply interface {
+	Message
+	Ports() []Port
+}
+
+type PortStatus interface {
+	Message
+	Port() Port
+}
+
+type FlowRemoved interface {
+	Message
+}
+
+type PacketIn interface {
+	Message
+	Data() []byte
+	InPort() uint32
+}
+
+// Match is a wildcard-capable flow match.
+type Match interface {
+	SetEtherSrc(mac, mask []byte) error
+	SetEtherDst(mac, mask []byte) error
+	SetInPort(port uint32)
+	SetVLANID(id uint16)
+	SetIPProtocol(protocol uint8)
+	SetUDPDstPort(port uint16)
+}
+
+// FlowMod is an OFPT_FLOW_MOD message under construction.
+type FlowMod interface {
+	Message
+	SetCookie(cookie uint64)
+	SetTableID(id uint8)
+	SetIdleTimeout(timeout uint16)
+	SetHardTimeout(timeout uint16)
+	SetPriority(priority uint16)
+	SetFlowMatch(match Match)
+	SetFlowInstruction(inst Instruction)
+}
+
+// Instruction is the instruction set a FlowMod installs once it matches.
+type Instruction interface {
+	GotoTable(tableID uint8)
+	ApplyAction(action Action)
+	WriteAction(action Action)
+}
+
+// Action is a single apply/write-actions entry.
+type Action interface {
+	SetOutPort(port OutPort)
+}
+
+// InPort identifies where a PacketOut should claim to have entered from.
+type InPort interface {
+	SetController()
+	SetValue(port uint32)
+}
+
+// OutPort identifies where an Action or PacketOut should send a packet.
+// Its zero value means flood.
+type OutPort interface {
+	SetController()
+	SetValue(port uint32)
+}
+
+func NewInPort() InPort {
+	return &port{}
+}
+
+func NewOutPort() OutPort {
+	return &port{}
+}
+
+type port struct {
+	value      uint32
+	controller bool
+}
+
+func (p *port) SetController() {
+	p.controller = true
+}
+
+func (p *port) SetValue(v uint32) {
+	p.value = v
+	p.controller = false
+}
+
+// PacketOut is an OFPT_PACKET_OUT message under construction.
+type PacketOut interface {
+	Message
+	SetInPort(port InPort)
+	SetAction(action Action)
+	SetData(data []byte)
+}
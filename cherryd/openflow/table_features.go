@@ -0,0 +1,29 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service Co., Ltd.,
+ * Kitae Kim <superkkt@sds.co.kr>
+ */
+
+package openflow
+
+// TableFeature describes one table's capabilities, as carried by a
+// TableFeaturesReply.
+type TableFeature interface {
+	ID() uint8
+	// Hardware reports whether the switch flagged this as a hardware table,
+	// as opposed to a software-emulated overflow table (e.g. the HP2920's
+	// low-performance Table-200).
+	Hardware() bool
+	Matches() []uint32
+	NextTables() []uint8
+	Instructions() []uint8
+	ApplyActions() []uint8
+	WriteActions() []uint8
+}
+
+// TableFeaturesReply is an OFPT_MULTIPART_REPLY/OFPMP_TABLE_FEATURES reply.
+type TableFeaturesReply interface {
+	Message
+	Tables() []TableFeature
+}
@@ -0,0 +1,16 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service Co., Ltd.,
+ * Kitae Kim <superkkt@sds.co.kr>
+ */
+
+// Package trans carries OpenFlow messages over a switch connection.
+package trans
+
+import "git.sds.co.kr/cherry.git/cherryd/openflow"
+
+// Writer sends a single OpenFlow message to a switch.
+type Writer interface {
+	Write(msg openflow.Message) error
+}
@@ -0,0 +1,105 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+)
+
+const arpPacketLength = 28
+
+// ARP operation codes.
+const (
+	ARPRequest uint16 = 1
+	ARPReply   uint16 = 2
+)
+
+// ARP is an Ethernet/IPv4 ARP packet (RFC 826).
+type ARP struct {
+	Operation uint16
+	SHA       net.HardwareAddr // sender hardware address
+	SPA       net.IP           // sender protocol address
+	THA       net.HardwareAddr // target hardware address
+	TPA       net.IP           // target protocol address
+}
+
+// NewARPRequest creates an ARP request asking who has targetIP, sent from
+// senderMAC/senderIP. Used for gratuitous announcements as well, where
+// targetIP equals senderIP.
+func NewARPRequest(senderMAC net.HardwareAddr, senderIP, targetIP net.IP) *ARP {
+	return &ARP{
+		Operation: ARPRequest,
+		SHA:       senderMAC,
+		SPA:       senderIP,
+		THA:       net.HardwareAddr([]byte{0, 0, 0, 0, 0, 0}),
+		TPA:       targetIP,
+	}
+}
+
+// NewARPReply creates an ARP reply telling targetMAC/targetIP that senderIP
+// belongs to senderMAC.
+func NewARPReply(senderMAC, targetMAC net.HardwareAddr, senderIP, targetIP net.IP) *ARP {
+	return &ARP{
+		Operation: ARPReply,
+		SHA:       senderMAC,
+		SPA:       senderIP,
+		THA:       targetMAC,
+		TPA:       targetIP,
+	}
+}
+
+func (r *ARP) MarshalBinary() ([]byte, error) {
+	sha, spa := r.SHA, r.SPA.To4()
+	tha, tpa := r.THA, r.TPA.To4()
+	if len(sha) != 6 || len(tha) != 6 || spa == nil || tpa == nil {
+		return nil, errors.New("protocol: invalid ARP hardware/protocol address")
+	}
+
+	v := make([]byte, arpPacketLength)
+	binary.BigEndian.PutUint16(v[0:2], 1)      // hardware type: Ethernet
+	binary.BigEndian.PutUint16(v[2:4], 0x0800) // protocol type: IPv4
+	v[4] = 6                                   // hardware address length
+	v[5] = 4                                   // protocol address length
+	binary.BigEndian.PutUint16(v[6:8], r.Operation)
+	copy(v[8:14], sha)
+	copy(v[14:18], spa)
+	copy(v[18:24], tha)
+	copy(v[24:28], tpa)
+
+	return v, nil
+}
+
+func (r *ARP) UnmarshalBinary(data []byte) error {
+	if len(data) < arpPacketLength {
+		return errors.New("protocol: ARP packet is too short")
+	}
+
+	r.Operation = binary.BigEndian.Uint16(data[6:8])
+	r.SHA = net.HardwareAddr(append([]byte(nil), data[8:14]...))
+	r.SPA = net.IP(append([]byte(nil), data[14:18]...))
+	r.THA = net.HardwareAddr(append([]byte(nil), data[18:24]...))
+	r.TPA = net.IP(append([]byte(nil), data[24:28]...))
+
+	return nil
+}
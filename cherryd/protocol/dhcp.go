@@ -0,0 +1,176 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+)
+
+// DHCP message types (option 53), as defined by RFC 2131/2132.
+const (
+	DHCPDiscover uint8 = 1
+	DHCPOffer    uint8 = 2
+	DHCPRequest  uint8 = 3
+	DHCPDecline  uint8 = 4
+	DHCPAck      uint8 = 5
+	DHCPNak      uint8 = 6
+	DHCPRelease  uint8 = 7
+)
+
+const (
+	dhcpOptMessageType uint8 = 53
+	dhcpOptLeaseTime   uint8 = 51
+	dhcpMinFixedLength       = 236
+	dhcpMagicCookie          = 0x63825363
+)
+
+// DHCP is a DHCPv4 packet (RFC 2131), excluding the bootp fixed fields this
+// controller doesn't need.
+type DHCP struct {
+	ClientHWAddr net.HardwareAddr
+	YourIPAddr   net.IP
+	ClientIPAddr net.IP
+	GatewayIP    net.IP
+	Options      map[uint8][]byte
+}
+
+// MessageType returns the option 53 DHCP message type.
+func (r *DHCP) MessageType() uint8 {
+	v := r.Options[dhcpOptMessageType]
+	if len(v) != 1 {
+		return 0
+	}
+	return v[0]
+}
+
+// LeaseTime returns the option 51 IP address lease time, or zero if absent.
+func (r *DHCP) LeaseTime() time.Duration {
+	v := r.Options[dhcpOptLeaseTime]
+	if len(v) != 4 {
+		return 0
+	}
+	return time.Duration(binary.BigEndian.Uint32(v)) * time.Second
+}
+
+func (r *DHCP) YourIP() net.IP {
+	return r.YourIPAddr
+}
+
+func (r *DHCP) ClientIP() net.IP {
+	return r.ClientIPAddr
+}
+
+func (r *DHCP) ClientMAC() net.HardwareAddr {
+	return r.ClientHWAddr
+}
+
+func (r *DHCP) MarshalBinary() ([]byte, error) {
+	v := make([]byte, dhcpMinFixedLength)
+	v[0] = 1 // BOOTREQUEST
+	v[1] = 1 // htype: Ethernet
+	v[2] = 6 // hlen
+	copy(v[12:16], r.ClientIPAddr.To4())
+	copy(v[16:20], r.YourIPAddr.To4())
+	copy(v[24:28], r.GatewayIP.To4())
+	copy(v[28:34], r.ClientHWAddr)
+
+	cookie := make([]byte, 4)
+	binary.BigEndian.PutUint32(cookie, dhcpMagicCookie)
+	v = append(v, cookie...)
+
+	for opt, data := range r.Options {
+		v = append(v, opt, byte(len(data)))
+		v = append(v, data...)
+	}
+	v = append(v, 0xFF) // end option
+
+	return v, nil
+}
+
+func (r *DHCP) UnmarshalBinary(data []byte) error {
+	if len(data) < dhcpMinFixedLength+4 {
+		return errors.New("protocol: DHCP packet is too short")
+	}
+	if binary.BigEndian.Uint32(data[dhcpMinFixedLength:dhcpMinFixedLength+4]) != dhcpMagicCookie {
+		return errors.New("protocol: missing DHCP magic cookie")
+	}
+
+	r.ClientIPAddr = net.IP(append([]byte(nil), data[12:16]...))
+	r.YourIPAddr = net.IP(append([]byte(nil), data[16:20]...))
+	r.GatewayIP = net.IP(append([]byte(nil), data[24:28]...))
+	r.ClientHWAddr = net.HardwareAddr(append([]byte(nil), data[28:34]...))
+
+	r.Options = make(map[uint8][]byte)
+	opts := data[dhcpMinFixedLength+4:]
+	for len(opts) >= 1 && opts[0] != 0xFF {
+		if len(opts) < 2 {
+			break
+		}
+		opt, optlen := opts[0], int(opts[1])
+		if len(opts) < 2+optlen {
+			break
+		}
+		r.Options[opt] = append([]byte(nil), opts[2:2+optlen]...)
+		opts = opts[2+optlen:]
+	}
+
+	return nil
+}
+
+// Relay rebuilds this DISCOVER/REQUEST as a relay-agent forward to server:
+// giaddr is set to giaddr, the relay agent's (this device's) own address, so
+// the server knows where to unicast its reply (RFC 2131 section 4.1) rather
+// than to itself. The packet is re-encapsulated in UDP/IPv4/Ethernet ready
+// for transmission.
+func (r *DHCP) Relay(server, giaddr net.IP) ([]byte, error) {
+	relayed := *r
+	relayed.GatewayIP = giaddr
+
+	dhcpBytes, err := relayed.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	udp := UDP{SrcPort: 67, DstPort: 67, Payload: dhcpBytes}
+	udpBytes, err := udp.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	ip := IPv4{SrcIP: giaddr, DstIP: server, Protocol: IPProtocolUDP, Payload: udpBytes}
+	ipBytes, err := ip.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	eth := Ethernet{
+		SrcMAC:  r.ClientHWAddr,
+		DstMAC:  net.HardwareAddr([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}),
+		Type:    0x0800,
+		Payload: ipBytes,
+	}
+
+	return eth.MarshalBinary()
+}
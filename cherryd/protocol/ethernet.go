@@ -0,0 +1,97 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+// Package protocol implements marshaling/unmarshaling for the packet
+// protocols the northbound apps inspect and emit.
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+)
+
+const (
+	ethernetHeaderLength = 14
+	vlanTagLength        = 4
+	vlanTPID             = 0x8100
+)
+
+// Ethernet is an IEEE 802.3 Ethernet II frame, optionally carrying a single
+// IEEE 802.1Q VLAN tag.
+type Ethernet struct {
+	SrcMAC net.HardwareAddr
+	DstMAC net.HardwareAddr
+	// VLAN is the 12-bit VLAN ID of this frame's 802.1Q tag, or nil if the
+	// frame is untagged.
+	VLAN    *uint16
+	Type    uint16
+	Payload []byte
+}
+
+func (r *Ethernet) MarshalBinary() ([]byte, error) {
+	if len(r.SrcMAC) != 6 || len(r.DstMAC) != 6 {
+		return nil, errors.New("protocol: invalid Ethernet MAC address length")
+	}
+
+	length := ethernetHeaderLength + len(r.Payload)
+	if r.VLAN != nil {
+		length += vlanTagLength
+	}
+	v := make([]byte, length)
+	copy(v[0:6], r.DstMAC)
+	copy(v[6:12], r.SrcMAC)
+
+	i := 12
+	if r.VLAN != nil {
+		binary.BigEndian.PutUint16(v[i:i+2], vlanTPID)
+		binary.BigEndian.PutUint16(v[i+2:i+4], *r.VLAN&0x0FFF)
+		i += vlanTagLength
+	}
+	binary.BigEndian.PutUint16(v[i:i+2], r.Type)
+	copy(v[i+2:], r.Payload)
+
+	return v, nil
+}
+
+func (r *Ethernet) UnmarshalBinary(data []byte) error {
+	if len(data) < ethernetHeaderLength {
+		return errors.New("protocol: Ethernet frame is too short")
+	}
+
+	r.DstMAC = net.HardwareAddr(append([]byte(nil), data[0:6]...))
+	r.SrcMAC = net.HardwareAddr(append([]byte(nil), data[6:12]...))
+
+	i := 12
+	r.VLAN = nil
+	if binary.BigEndian.Uint16(data[i:i+2]) == vlanTPID {
+		if len(data) < ethernetHeaderLength+vlanTagLength {
+			return errors.New("protocol: truncated 802.1Q VLAN tag")
+		}
+		vlan := binary.BigEndian.Uint16(data[i+2:i+4]) & 0x0FFF
+		r.VLAN = &vlan
+		i += vlanTagLength
+	}
+	r.Type = binary.BigEndian.Uint16(data[i : i+2])
+	r.Payload = append([]byte(nil), data[i+2:]...)
+
+	return nil
+}
@@ -0,0 +1,99 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+)
+
+// IPProtocolUDP is the IPv4 protocol number assigned to UDP.
+const IPProtocolUDP uint8 = 17
+
+const ipv4MinHeaderLength = 20
+
+// IPv4 is an IPv4 packet, without options.
+type IPv4 struct {
+	SrcIP    net.IP
+	DstIP    net.IP
+	Protocol uint8
+	Payload  []byte
+}
+
+func (r *IPv4) MarshalBinary() ([]byte, error) {
+	src := r.SrcIP.To4()
+	dst := r.DstIP.To4()
+	if src == nil || dst == nil {
+		return nil, errors.New("protocol: IPv4 address must be an IPv4 (not IPv6) address")
+	}
+
+	v := make([]byte, ipv4MinHeaderLength+len(r.Payload))
+	v[0] = 0x45 // version 4, header length 5 words
+	binary.BigEndian.PutUint16(v[2:4], uint16(len(v)))
+	v[9] = r.Protocol
+	copy(v[12:16], src)
+	copy(v[16:20], dst)
+	copy(v[20:], r.Payload)
+
+	// Header checksum is computed over the header alone, with the checksum
+	// field itself zeroed, and must be filled in last.
+	binary.BigEndian.PutUint16(v[10:12], ipv4Checksum(v[:ipv4MinHeaderLength]))
+
+	return v, nil
+}
+
+// ipv4Checksum computes the IPv4 header checksum (RFC 791 section 3.1): the
+// 16-bit one's complement of the one's complement sum of the header's 16-bit
+// words, with the checksum field itself treated as zero.
+func ipv4Checksum(header []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(header); i += 2 {
+		if i == 10 {
+			continue // checksum field itself
+		}
+		sum += uint32(binary.BigEndian.Uint16(header[i : i+2]))
+	}
+	for sum>>16 != 0 {
+		sum = (sum & 0xFFFF) + (sum >> 16)
+	}
+
+	return ^uint16(sum)
+}
+
+func (r *IPv4) UnmarshalBinary(data []byte) error {
+	if len(data) < ipv4MinHeaderLength {
+		return errors.New("protocol: IPv4 packet is too short")
+	}
+
+	headerLen := int(data[0]&0x0F) * 4
+	if headerLen < ipv4MinHeaderLength || len(data) < headerLen {
+		return errors.New("protocol: invalid IPv4 header length")
+	}
+
+	r.Protocol = data[9]
+	r.SrcIP = net.IP(append([]byte(nil), data[12:16]...))
+	r.DstIP = net.IP(append([]byte(nil), data[16:20]...))
+	r.Payload = append([]byte(nil), data[headerLen:]...)
+
+	return nil
+}
@@ -0,0 +1,58 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const udpHeaderLength = 8
+
+// UDP is a UDP datagram.
+type UDP struct {
+	SrcPort uint16
+	DstPort uint16
+	Payload []byte
+}
+
+func (r *UDP) MarshalBinary() ([]byte, error) {
+	v := make([]byte, udpHeaderLength+len(r.Payload))
+	binary.BigEndian.PutUint16(v[0:2], r.SrcPort)
+	binary.BigEndian.PutUint16(v[2:4], r.DstPort)
+	binary.BigEndian.PutUint16(v[4:6], uint16(len(v)))
+	copy(v[8:], r.Payload)
+
+	return v, nil
+}
+
+func (r *UDP) UnmarshalBinary(data []byte) error {
+	if len(data) < udpHeaderLength {
+		return errors.New("protocol: UDP datagram is too short")
+	}
+
+	r.SrcPort = binary.BigEndian.Uint16(data[0:2])
+	r.DstPort = binary.BigEndian.Uint16(data[2:4])
+	r.Payload = append([]byte(nil), data[udpHeaderLength:]...)
+
+	return nil
+}